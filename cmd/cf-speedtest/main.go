@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	speedtest "github.com/sartoopjj/cloudflare-speedtest/speedtest"
+)
+
+// autoPingsPerIP is how many trace probes --auto averages per candidate IP.
+const autoPingsPerIP = 3
+
+func main() {
+	var (
+		jsonOutput    = flag.Bool("json", false, "output the result as JSON")
+		unixOutput    = flag.Bool("unix", false, "output the result as unix-style key=value pairs")
+		csvPath       = flag.String("csv", "", "append the result as a CSV row to this file")
+		verbose       = flag.Bool("verbose", false, "log each request (each interval with --duration) as it completes")
+		packetSize    = flag.Int("packet-size", 100*1024*1024, "size of each packet in bytes")
+		packetCount   = flag.Int("packet-count", 5, "number of packets to send per direction")
+		duration      = flag.Duration("duration", 0, "run a duration-based streaming test instead of a fixed packet count")
+		ip            = flag.String("ip", "", "specific Cloudflare IP to test against (DNS if empty)")
+		concurrency   = flag.Int("concurrency", 1, "number of simultaneous transfers per direction")
+		auto          = flag.Bool("auto", false, "auto-select the lowest-latency Cloudflare edge IP before testing")
+		ping          = flag.Bool("ping", false, "measure RTT, jitter, and packet loss before testing")
+		proxy         = flag.String("proxy", "", "proxy URL to route traffic through (http://, https://, or socks5://)")
+		sourceIP      = flag.String("source", "", "local source IP to bind outgoing connections to")
+		dnsBindSource = flag.Bool("dns-bind-source", false, "also route DNS resolution through --source")
+	)
+	flag.Parse()
+
+	config := speedtest.SpeedTestConfig{
+		CloudflareIP:  *ip,
+		PacketSize:    *packetSize,
+		PacketCount:   *packetCount,
+		Concurrency:   *concurrency,
+		Duration:      *duration,
+		Verbose:       *verbose,
+		MeasureLoss:   *ping,
+		Proxy:         *proxy,
+		SourceIP:      *sourceIP,
+		DNSBindSource: *dnsBindSource,
+	}
+
+	var st *speedtest.SpeedTester
+	if *auto {
+		var err error
+		st, err = speedtest.NewSpeedTesterAuto(context.Background(), config, nil, autoPingsPerIP)
+		if err != nil {
+			log.Fatalf("Auto edge selection failed: %v", err)
+		}
+	} else {
+		var err error
+		st, err = speedtest.NewSpeedTester(config)
+		if err != nil {
+			log.Fatalf("Failed to build speed tester: %v", err)
+		}
+	}
+
+	var result *speedtest.SpeedTestResult
+	var err error
+	if *duration > 0 {
+		result, err = runStream(context.Background(), st, *verbose)
+	} else {
+		result, err = st.RunTest()
+	}
+	if err != nil {
+		log.Fatalf("Speed test failed: %v", err)
+	}
+
+	switch {
+	case *jsonOutput:
+		data, err := result.MarshalJSON()
+		if err != nil {
+			log.Fatalf("Failed to marshal JSON: %v", err)
+		}
+		fmt.Println(string(data))
+	case *unixOutput:
+		fmt.Println(result.String())
+	default:
+		fmt.Printf("Average Download Speed: %.2f Mb/s\n", result.AvgDownloadSpeedMb)
+		fmt.Printf("Average Upload Speed: %.2f Mb/s\n", result.AvgUploadSpeedMb)
+		fmt.Printf("Average Download Latency: %v\n", result.AvgDownloadLatency)
+		fmt.Printf("Average Upload Latency: %v\n", result.AvgUploadLatency)
+	}
+
+	if *csvPath != "" {
+		if err := result.AppendCSV(*csvPath, time.Now(), st.TargetIP()); err != nil {
+			log.Fatalf("Failed to write CSV: %v", err)
+		}
+	}
+}
+
+// runStream drives a duration-based streaming test to completion and
+// summarizes it into a SpeedTestResult so it can use the same output
+// formatting as the packet-count based RunTest. Only the speeds and ping
+// stats are filled in; Colo, Concurrency, PacketSize, and the latencies are
+// left zero.
+func runStream(ctx context.Context, st *speedtest.SpeedTester, verbose bool) (*speedtest.SpeedTestResult, error) {
+	stream, pingStats, err := st.RunTestStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &speedtest.SpeedTestResult{
+		MinRTT:      pingStats.MinRTT,
+		MaxRTT:      pingStats.MaxRTT,
+		AvgRTT:      pingStats.AvgRTT,
+		Jitter:      pingStats.Jitter,
+		LossPercent: pingStats.LossPercent,
+	}
+
+	for res := range stream {
+		if res.Err != nil {
+			return nil, fmt.Errorf("%s test failed: %w", res.Direction, res.Err)
+		}
+
+		speed := mbps(res.Bytes, res.IntervalEnd.Sub(res.IntervalStart))
+
+		if !res.Total {
+			if verbose {
+				fmt.Printf("[%s] %.2f Mb/s\n", res.Direction, speed)
+			}
+			continue
+		}
+
+		switch res.Direction {
+		case speedtest.Upload:
+			result.AvgUploadSpeedMb = speed
+		case speedtest.Download:
+			result.AvgDownloadSpeedMb = speed
+		}
+	}
+
+	return result, nil
+}
+
+// mbps converts a byte count moved over elapsed into megabits per second.
+func mbps(bytes int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return (float64(bytes) / elapsed.Seconds()) / 125000
+}
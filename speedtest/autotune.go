@@ -0,0 +1,77 @@
+package speedtest
+
+import (
+	"fmt"
+	"time"
+)
+
+// Autotune parameters, loosely modeled on MinIO's speedtest autotune
+// handler: start small and single-threaded, then double whichever knob
+// keeps paying off.
+const (
+	autotuneInitialConcurrency = 1
+	autotuneInitialPacketSize  = 1 * 1024 * 1024 // 1 MiB
+	autotuneProbeCount         = 2
+	autotuneImprovementThresh  = 0.05 // keep doubling while throughput gains > 5%
+	autotuneMaxConcurrency     = 64
+	autotuneMaxPacketSize      = 128 * 1024 * 1024
+)
+
+// autotune probes download throughput at increasing concurrency and packet
+// sizes, doubling whichever knob keeps improving throughput by more than
+// autotuneImprovementThresh, and stops once neither knob improves. An error
+// from the initial probe is returned; a later probe that errors counts as
+// no improvement for its knob.
+// The winning settings are written back into st.config so the subsequent
+// upload/download tests use them.
+func (st *SpeedTester) autotune() error {
+	concurrency := autotuneInitialConcurrency
+	packetSize := autotuneInitialPacketSize
+
+	bestSpeed, err := st.probeThroughput(concurrency, packetSize)
+	if err != nil {
+		return fmt.Errorf("initial probe failed: %w", err)
+	}
+
+	for concurrency < autotuneMaxConcurrency || packetSize < autotuneMaxPacketSize {
+		improved := false
+
+		if concurrency < autotuneMaxConcurrency {
+			if speed, err := st.probeThroughput(concurrency*2, packetSize); err == nil && speed > bestSpeed*(1+autotuneImprovementThresh) {
+				concurrency *= 2
+				bestSpeed = speed
+				improved = true
+			}
+		}
+
+		if packetSize < autotuneMaxPacketSize {
+			if speed, err := st.probeThroughput(concurrency, packetSize*2); err == nil && speed > bestSpeed*(1+autotuneImprovementThresh) {
+				packetSize *= 2
+				bestSpeed = speed
+				improved = true
+			}
+		}
+
+		if !improved {
+			break
+		}
+	}
+
+	if st.config.Verbose {
+		fmt.Printf("Autotune converged on concurrency=%d packetSize=%d (%.2f Mb/s)\n", concurrency, packetSize, bestSpeed)
+	}
+
+	st.config.Concurrency = concurrency
+	st.config.PacketSize = packetSize
+
+	return nil
+}
+
+// probeThroughput runs a short download batch at the given concurrency and
+// packet size and returns the measured throughput in Mb/s.
+func (st *SpeedTester) probeThroughput(concurrency, packetSize int) (float64, error) {
+	speedMb, _, err := st.runParallel(concurrency, packetSize, autotuneProbeCount*concurrency, func(n int) (time.Duration, error) {
+		return st.downloadBatch(packetSize, n)
+	})
+	return speedMb, err
+}
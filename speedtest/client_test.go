@@ -0,0 +1,69 @@
+package speedtest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestNewClientRejectsMalformedProxy(t *testing.T) {
+	_, err := newClient(SpeedTestConfig{Proxy: "http://%zz"})
+	if err == nil {
+		t.Fatal("newClient() err = nil, want an error for a malformed proxy URL")
+	}
+}
+
+func TestNewClientSetsProxy(t *testing.T) {
+	client, err := newClient(SpeedTestConfig{Proxy: "http://127.0.0.1:8080"})
+	if err != nil {
+		t.Fatalf("newClient: %v", err)
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("client.Transport is %T, want *http.Transport", client.Transport)
+	}
+	if transport.Proxy == nil {
+		t.Fatal("transport.Proxy is nil, want it set from config.Proxy")
+	}
+
+	req, _ := http.NewRequest("GET", "https://speed.cloudflare.com", nil)
+	proxyURL, err := transport.Proxy(req)
+	if err != nil {
+		t.Fatalf("transport.Proxy(req): %v", err)
+	}
+	if proxyURL == nil || proxyURL.String() != "http://127.0.0.1:8080" {
+		t.Errorf("transport.Proxy(req) = %v, want http://127.0.0.1:8080", proxyURL)
+	}
+}
+
+func TestNewClientBindsSourceIP(t *testing.T) {
+	client, err := newClient(SpeedTestConfig{SourceIP: "127.0.0.2"})
+	if err != nil {
+		t.Fatalf("newClient: %v", err)
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("client.Transport is %T, want *http.Transport", client.Transport)
+	}
+	if transport.DialContext == nil {
+		t.Fatal("transport.DialContext is nil")
+	}
+
+	// Dialing a closed local port fails fast but still exercises the
+	// dialer far enough to prove it tried to bind to SourceIP.
+	_, dialErr := transport.DialContext(context.Background(), "tcp", "127.0.0.1:1")
+	if dialErr == nil {
+		t.Fatal("expected dial to 127.0.0.1:1 to fail")
+	}
+
+	opErr, ok := dialErr.(*net.OpError)
+	if !ok || opErr.Source == nil {
+		t.Fatalf("dial error = %v, want a *net.OpError with a bound Source address", dialErr)
+	}
+	if host, _, _ := net.SplitHostPort(opErr.Source.String()); host != "127.0.0.2" {
+		t.Errorf("dialer bound to %q, want 127.0.0.2", host)
+	}
+}
@@ -0,0 +1,116 @@
+package speedtest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// csvHeader is written as the first line of a new CSV file by AppendCSV.
+var csvHeader = []string{
+	"timestamp", "ip", "colo",
+	"down_mbps", "up_mbps",
+	"down_latency_ms", "up_latency_ms",
+	"packet_loss",
+}
+
+// resultJSON is the on-wire shape produced by MarshalJSON, rendering
+// latencies as Go duration strings rather than raw nanosecond counts.
+type resultJSON struct {
+	Colo               string  `json:"colo,omitempty"`
+	Concurrency        int     `json:"concurrency"`
+	PacketSize         int     `json:"packet_size"`
+	AvgDownloadSpeedMb float64 `json:"avg_download_speed_mbps"`
+	AvgUploadSpeedMb   float64 `json:"avg_upload_speed_mbps"`
+	AvgDownloadLatency string  `json:"avg_download_latency"`
+	AvgUploadLatency   string  `json:"avg_upload_latency"`
+	MinRTT             string  `json:"min_rtt,omitempty"`
+	MaxRTT             string  `json:"max_rtt,omitempty"`
+	AvgRTT             string  `json:"avg_rtt,omitempty"`
+	Jitter             string  `json:"jitter,omitempty"`
+	LossPercent        float64 `json:"loss_percent"`
+}
+
+// MarshalJSON implements json.Marshaler for SpeedTestResult.
+func (r *SpeedTestResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(resultJSON{
+		Colo:               r.Colo,
+		Concurrency:        r.Concurrency,
+		PacketSize:         r.PacketSize,
+		AvgDownloadSpeedMb: r.AvgDownloadSpeedMb,
+		AvgUploadSpeedMb:   r.AvgUploadSpeedMb,
+		AvgDownloadLatency: r.AvgDownloadLatency.String(),
+		AvgUploadLatency:   r.AvgUploadLatency.String(),
+		MinRTT:             optionalDuration(r.MinRTT),
+		MaxRTT:             optionalDuration(r.MaxRTT),
+		AvgRTT:             optionalDuration(r.AvgRTT),
+		Jitter:             optionalDuration(r.Jitter),
+		LossPercent:        r.LossPercent,
+	})
+}
+
+// optionalDuration renders d as a Go duration string, or "" when it's zero
+// (e.g. MeasureLoss wasn't set), so the JSON field is omitted via omitempty.
+func optionalDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// String renders the result as a single line of unix-style key=value
+// pairs, suitable for scripting.
+func (r *SpeedTestResult) String() string {
+	return fmt.Sprintf(
+		"colo=%s concurrency=%d packet_size=%d down_mbps=%.2f up_mbps=%.2f down_latency=%s up_latency=%s avg_rtt=%s jitter=%s loss_percent=%.2f",
+		r.Colo, r.Concurrency, r.PacketSize, r.AvgDownloadSpeedMb, r.AvgUploadSpeedMb, r.AvgDownloadLatency, r.AvgUploadLatency, r.AvgRTT, r.Jitter, r.LossPercent,
+	)
+}
+
+// MarshalCSV renders the result as a single CSV row (no header), using ts
+// and ip to fill in the columns the result itself doesn't carry.
+func (r *SpeedTestResult) MarshalCSV(ts time.Time, ip string) []string {
+	return []string{
+		ts.Format(time.RFC3339),
+		ip,
+		r.Colo,
+		strconv.FormatFloat(r.AvgDownloadSpeedMb, 'f', 2, 64),
+		strconv.FormatFloat(r.AvgUploadSpeedMb, 'f', 2, 64),
+		strconv.FormatFloat(float64(r.AvgDownloadLatency.Milliseconds()), 'f', 2, 64),
+		strconv.FormatFloat(float64(r.AvgUploadLatency.Milliseconds()), 'f', 2, 64),
+		strconv.FormatFloat(r.LossPercent, 'f', 2, 64),
+	}
+}
+
+// AppendCSV appends the result as a row to path, writing csvHeader first if
+// the file doesn't already exist, mirroring CloudflareSpeedTest's ExportCsv
+// so scripted long-term monitoring can tail one growing file.
+func (r *SpeedTestResult) AppendCSV(path string, ts time.Time, ip string) error {
+	needsHeader := false
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		needsHeader = true
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+
+	if needsHeader {
+		if err := w.Write(csvHeader); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+	if err := w.Write(r.MarshalCSV(ts, ip)); err != nil {
+		return fmt.Errorf("failed to write CSV row: %w", err)
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,95 @@
+package speedtest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleResult() *SpeedTestResult {
+	return &SpeedTestResult{
+		AvgDownloadSpeedMb: 123.456,
+		AvgUploadSpeedMb:   45.6,
+		AvgDownloadLatency: 12 * time.Millisecond,
+		AvgUploadLatency:   8 * time.Millisecond,
+		Colo:               "LHR",
+		Concurrency:        4,
+		PacketSize:         1024,
+		LossPercent:        1.5,
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	data, err := sampleResult().MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if decoded["colo"] != "LHR" {
+		t.Errorf("colo = %v, want LHR", decoded["colo"])
+	}
+	if decoded["avg_download_latency"] != "12ms" {
+		t.Errorf("avg_download_latency = %v, want 12ms", decoded["avg_download_latency"])
+	}
+	if _, present := decoded["min_rtt"]; present {
+		t.Errorf("min_rtt should be omitted when zero, got %v", decoded["min_rtt"])
+	}
+}
+
+func TestString(t *testing.T) {
+	got := sampleResult().String()
+	want := "colo=LHR concurrency=4 packet_size=1024 down_mbps=123.46 up_mbps=45.60 down_latency=12ms up_latency=8ms avg_rtt=0s jitter=0s loss_percent=1.50"
+
+	if got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestMarshalCSV(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	got := sampleResult().MarshalCSV(ts, "198.51.100.1")
+	want := []string{"2026-01-02T03:04:05Z", "198.51.100.1", "LHR", "123.46", "45.60", "12.00", "8.00", "1.50"}
+
+	if len(got) != len(want) {
+		t.Fatalf("MarshalCSV() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("MarshalCSV()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAppendCSVWritesHeaderOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv")
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	result := sampleResult()
+	if err := result.AppendCSV(path, ts, "198.51.100.1"); err != nil {
+		t.Fatalf("first AppendCSV: %v", err)
+	}
+	if err := result.AppendCSV(path, ts, "198.51.100.1"); err != nil {
+		t.Fatalf("second AppendCSV: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) != 3 { // header + 2 rows
+		t.Fatalf("got %d lines, want 3: %q", len(lines), data)
+	}
+	if lines[0] != strings.Join(csvHeader, ",") {
+		t.Errorf("header = %q, want %q", lines[0], strings.Join(csvHeader, ","))
+	}
+}
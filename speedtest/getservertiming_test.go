@@ -0,0 +1,34 @@
+package speedtest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGetServerTiming(t *testing.T) {
+	st := &SpeedTester{}
+
+	cases := []struct {
+		name   string
+		header string
+		want   time.Duration
+	}{
+		{"missing", "", 0},
+		{"malformed", "cfRequestDuration", 0},
+		{"valid", "cfRequestDuration;dur=12", 12 * time.Millisecond},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			headers := http.Header{}
+			if c.header != "" {
+				headers.Set("Server-Timing", c.header)
+			}
+
+			if got := st.getServerTiming(&headers); got != c.want {
+				t.Errorf("getServerTiming(%q) = %v, want %v", c.header, got, c.want)
+			}
+		})
+	}
+}
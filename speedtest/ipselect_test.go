@@ -0,0 +1,55 @@
+package speedtest
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestFirstHostIP(t *testing.T) {
+	cases := []struct {
+		cidr string
+		want string
+	}{
+		{"198.51.100.0/24", "198.51.100.1"},
+		{"203.0.113.8/29", "203.0.113.9"},
+	}
+
+	for _, c := range cases {
+		_, ipNet, err := net.ParseCIDR(c.cidr)
+		if err != nil {
+			t.Fatalf("ParseCIDR(%q): %v", c.cidr, err)
+		}
+
+		if got := firstHostIP(ipNet); got != c.want {
+			t.Errorf("firstHostIP(%q) = %q, want %q", c.cidr, got, c.want)
+		}
+	}
+}
+
+func TestFirstHostIPRejectsIPv6(t *testing.T) {
+	_, ipNet, err := net.ParseCIDR("2606:4700::/32")
+	if err != nil {
+		t.Fatalf("ParseCIDR: %v", err)
+	}
+
+	if got := firstHostIP(ipNet); got != "" {
+		t.Errorf("firstHostIP(IPv6) = %q, want empty", got)
+	}
+}
+
+func TestParseColoFromTrace(t *testing.T) {
+	body := strings.NewReader("fl=150f40\nh=speed.cloudflare.com\nip=198.51.100.1\ncolo=LHR\nts=1234\n")
+
+	if got := parseColoFromTrace(body); got != "LHR" {
+		t.Errorf("parseColoFromTrace() = %q, want %q", got, "LHR")
+	}
+}
+
+func TestParseColoFromTraceMissing(t *testing.T) {
+	body := strings.NewReader("fl=150f40\nh=speed.cloudflare.com\n")
+
+	if got := parseColoFromTrace(body); got != "" {
+		t.Errorf("parseColoFromTrace() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,34 @@
+package speedtest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCount(t *testing.T) {
+	cases := []struct {
+		total int
+		n     int
+		want  []int
+	}{
+		{10, 1, []int{10}},
+		{10, 2, []int{5, 5}},
+		{10, 3, []int{4, 3, 3}},
+		{0, 4, []int{0, 0, 0, 0}},
+	}
+
+	for _, c := range cases {
+		got := splitCount(c.total, c.n)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("splitCount(%d, %d) = %v, want %v", c.total, c.n, got, c.want)
+		}
+
+		sum := 0
+		for _, n := range got {
+			sum += n
+		}
+		if sum != c.total {
+			t.Errorf("splitCount(%d, %d) sums to %d, want %d", c.total, c.n, sum, c.total)
+		}
+	}
+}
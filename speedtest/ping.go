@@ -0,0 +1,94 @@
+package speedtest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultPingCount = 10
+
+// PingStats summarizes a batch of RTT probes against the test target.
+type PingStats struct {
+	MinRTT      time.Duration
+	MaxRTT      time.Duration
+	AvgRTT      time.Duration
+	Jitter      time.Duration
+	LossPercent float64
+}
+
+// Ping sends count probes against the configured Cloudflare IP (or
+// speed.cloudflare.com when none is set) and returns round-trip
+// statistics: MinRTT/MaxRTT/AvgRTT, Jitter (the mean absolute deviation of
+// successive RTT deltas), and LossPercent. ICMP echo needs a raw socket
+// (CAP_NET_RAW) this package doesn't require anywhere else, so probes are
+// lightweight GETs against the trace endpoint instead -- the same fallback
+// speedtest-go uses when ICMP isn't available. Probes go through st.client,
+// so they honor config.Proxy/SourceIP the same way the transfer test does.
+func (st *SpeedTester) Ping(ctx context.Context, count int) (PingStats, error) {
+	var rtts []time.Duration
+
+	for i := 0; i < count; i++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", cloudflareTraceURL, nil)
+		if err != nil {
+			return PingStats{}, err
+		}
+
+		start := time.Now()
+		resp, err := st.client.Do(req)
+		if err != nil {
+			continue
+		}
+		rtt := time.Since(start) - st.getServerTiming(&resp.Header)
+		resp.Body.Close()
+
+		rtts = append(rtts, rtt)
+	}
+
+	lossPercent := float64(count-len(rtts)) / float64(count) * 100
+
+	if len(rtts) == 0 {
+		return PingStats{LossPercent: lossPercent}, fmt.Errorf("all %d pings to %s failed", count, cloudflareTraceURL)
+	}
+
+	stats := PingStats{
+		MinRTT:      rtts[0],
+		MaxRTT:      rtts[0],
+		LossPercent: lossPercent,
+	}
+
+	var total time.Duration
+	for _, rtt := range rtts {
+		total += rtt
+		if rtt < stats.MinRTT {
+			stats.MinRTT = rtt
+		}
+		if rtt > stats.MaxRTT {
+			stats.MaxRTT = rtt
+		}
+	}
+	stats.AvgRTT = total / time.Duration(len(rtts))
+	stats.Jitter = meanAbsoluteDeviation(rtts)
+
+	return stats, nil
+}
+
+// meanAbsoluteDeviation returns the mean absolute difference between
+// successive RTTs, the standard jitter definition used by iperf/ping tools.
+func meanAbsoluteDeviation(rtts []time.Duration) time.Duration {
+	if len(rtts) < 2 {
+		return 0
+	}
+
+	var totalDelta time.Duration
+	for i := 1; i < len(rtts); i++ {
+		delta := rtts[i] - rtts[i-1]
+		if delta < 0 {
+			delta = -delta
+		}
+		totalDelta += delta
+	}
+
+	return totalDelta / time.Duration(len(rtts)-1)
+}
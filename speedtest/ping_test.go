@@ -0,0 +1,36 @@
+package speedtest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMeanAbsoluteDeviation(t *testing.T) {
+	cases := []struct {
+		name string
+		rtts []time.Duration
+		want time.Duration
+	}{
+		{"empty", nil, 0},
+		{"single", []time.Duration{10 * time.Millisecond}, 0},
+		{
+			"constant",
+			[]time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond},
+			0,
+		},
+		{
+			// deltas: +20ms, -10ms -> mean absolute deviation (20+10)/2 = 15ms
+			"varying",
+			[]time.Duration{10 * time.Millisecond, 30 * time.Millisecond, 20 * time.Millisecond},
+			15 * time.Millisecond,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := meanAbsoluteDeviation(c.rtts); got != c.want {
+				t.Errorf("meanAbsoluteDeviation(%v) = %v, want %v", c.rtts, got, c.want)
+			}
+		})
+	}
+}
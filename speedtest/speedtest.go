@@ -1,24 +1,75 @@
 package speedtest
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
 	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	cloudflareTraceURL      = "https://speed.cloudflare.com/cdn-cgi/trace"
+	cloudflareIPv4RangesURL = "https://www.cloudflare.com/ips-v4"
+
+	defaultSelectConcurrency = 8
+	defaultSelectTimeout     = 5 * time.Second
+)
+
 // SpeedTestConfig contains the configuration for the speed test.
 type SpeedTestConfig struct {
 	CloudflareIP string // Specific IP of the Cloudflare server
 	PacketSize   int    // Size of each packet (in bytes)
 	PacketCount  int    // Number of packets to send
 	Verbose      bool
+
+	// SelectConcurrency controls how many candidate IPs SelectFastestIP
+	// probes at once. Defaults to defaultSelectConcurrency when <= 0.
+	SelectConcurrency int
+	// SelectTimeout bounds how long SelectFastestIP waits for a single
+	// probe against a candidate IP. Defaults to defaultSelectTimeout when <= 0.
+	SelectTimeout time.Duration
+
+	// Duration is how long each direction of RunTestStream runs for,
+	// clamped to [MinDuration, MaxDuration]. Defaults to DefaultDuration
+	// when <= 0.
+	Duration time.Duration
+	// ReportInterval is how often RunTestStream flushes an interval
+	// Result. Defaults to defaultReportInterval when <= 0.
+	ReportInterval time.Duration
+
+	// Concurrency is how many simultaneous HTTP transfers testUpload and
+	// testDownload fan out across. Defaults to 1 when <= 0. Ignored (and
+	// overwritten) when Autotune is set.
+	Concurrency int
+	// Autotune, when set, probes throughput at increasing concurrency and
+	// packet sizes before RunTest's upload/download test and uses whatever
+	// it converges on. RunTestStream does not autotune.
+	Autotune bool
+
+	// MeasureLoss, when set, runs Ping before the upload/download test and
+	// folds its stats into the result.
+	MeasureLoss bool
+
+	// Proxy, when set, routes the test's HTTP traffic through this proxy
+	// URL. http://, https://, and socks5:// schemes are supported.
+	Proxy string
+	// SourceIP binds the outgoing TCP socket (and, with DNSBindSource,
+	// DNS resolution) to this local address, for multi-homed hosts.
+	SourceIP string
+	// DNSBindSource routes DNS resolution through SourceIP as well.
+	// Ignored if SourceIP is empty.
+	DNSBindSource bool
 }
 
 // SpeedTestResult holds the result of the speed test.
@@ -27,6 +78,24 @@ type SpeedTestResult struct {
 	AvgDownloadSpeedMb float64
 	AvgUploadLatency   time.Duration
 	AvgDownloadLatency time.Duration
+
+	// Colo is the Cloudflare datacenter code (e.g. "LHR") the test hit,
+	// populated when the IP was chosen via SelectFastestIP/NewSpeedTesterAuto.
+	Colo string
+
+	// Concurrency and PacketSize record the settings the test actually
+	// ran with, which may differ from the requested config when Autotune
+	// converged on something else.
+	Concurrency int
+	PacketSize  int
+
+	// MinRTT, MaxRTT, AvgRTT, Jitter, and LossPercent are populated from
+	// Ping when config.MeasureLoss is set; zero otherwise.
+	MinRTT      time.Duration
+	MaxRTT      time.Duration
+	AvgRTT      time.Duration
+	Jitter      time.Duration
+	LossPercent float64
 }
 
 // SpeedTester struct that will perform the speed test.
@@ -34,36 +103,114 @@ type SpeedTester struct {
 	config SpeedTestConfig
 	client *http.Client
 	testID int64
+	colo   string
 }
 
 // NewSpeedTester creates a new SpeedTester with a specific configuration.
-func NewSpeedTester(config SpeedTestConfig) *SpeedTester {
-	if config.CloudflareIP != "" {
-		return &SpeedTester{
-			config: config,
-			client: &http.Client{
-				Transport: &http.Transport{
-					DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-						dialer := &net.Dialer{
-							Timeout: 10 * time.Second,
-						}
-						return dialer.DialContext(ctx, network, net.JoinHostPort(config.CloudflareIP, "443"))
-					},
+func NewSpeedTester(config SpeedTestConfig) (*SpeedTester, error) {
+	client, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpeedTester{
+		config: config,
+		client: client,
+		testID: rand.Int63(),
+	}, nil
+}
+
+// TargetIP returns the Cloudflare IP the test is pinned to, including one
+// chosen by SelectFastestIP/NewSpeedTesterAuto; empty when the test is
+// resolving speed.cloudflare.com via plain DNS.
+func (st *SpeedTester) TargetIP() string {
+	return st.config.CloudflareIP
+}
+
+// NewSpeedTesterAuto creates a SpeedTester after selecting the Cloudflare
+// edge IP with the lowest latency out of candidates. If candidates is
+// empty, it probes addresses drawn from Cloudflare's published IPv4 ranges
+// instead. pingsPerIP controls how many trace probes are averaged per
+// candidate.
+func NewSpeedTesterAuto(ctx context.Context, config SpeedTestConfig, candidates []string, pingsPerIP int) (*SpeedTester, error) {
+	st, err := NewSpeedTester(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := st.SelectFastestIP(ctx, candidates, pingsPerIP); err != nil {
+		return nil, fmt.Errorf("failed to select fastest IP: %w", err)
+	}
+
+	return st, nil
+}
+
+// newClient builds the http.Client used for the test: pinning the dialer to
+// config.CloudflareIP when one is set and no proxy is configured, binding the outgoing socket (and
+// optionally DNS resolution) to config.SourceIP, and routing through
+// config.Proxy when given. It returns an error rather than silently
+// dropping a malformed config.Proxy URL.
+func newClient(config SpeedTestConfig) (*http.Client, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	if config.SourceIP != "" {
+		dialer.LocalAddr = &net.TCPAddr{IP: net.ParseIP(config.SourceIP)}
+
+		if config.DNSBindSource {
+			dialer.Resolver = &net.Resolver{
+				PreferGo: true,
+				Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+					resolverDialer := &net.Dialer{
+						Timeout:   10 * time.Second,
+						LocalAddr: &net.UDPAddr{IP: net.ParseIP(config.SourceIP)},
+					}
+					return resolverDialer.DialContext(ctx, network, address)
 				},
-			},
-			testID: rand.Int63(),
+			}
 		}
-	} else {
-		return &SpeedTester{
-			config: config,
-			client: &http.Client{},
-			testID: rand.Int63(),
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			// Only pin to CloudflareIP when dialing directly: with a
+			// proxy configured, DialContext is called with the proxy's
+			// address, and rewriting it here would bypass the proxy
+			// entirely instead of tunneling through it.
+			if config.CloudflareIP != "" && config.Proxy == "" {
+				addr = net.JoinHostPort(config.CloudflareIP, "443")
+			}
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", config.Proxy, err)
 		}
+		transport.Proxy = http.ProxyURL(proxyURL)
 	}
+
+	return &http.Client{Transport: transport}, nil
 }
 
 // RunTest performs the upload and download speed test and returns the result.
 func (st *SpeedTester) RunTest() (*SpeedTestResult, error) {
+	if st.config.Autotune {
+		if err := st.autotune(); err != nil {
+			return nil, fmt.Errorf("autotune failed: %w", err)
+		}
+	}
+
+	var pingStats PingStats
+	if st.config.MeasureLoss {
+		stats, err := st.Ping(context.Background(), defaultPingCount)
+		if err != nil {
+			return nil, fmt.Errorf("ping failed: %w", err)
+		}
+		pingStats = stats
+	}
+
 	uploadSpeed, uploadLatency, err := st.testUpload()
 	if err != nil {
 		return nil, fmt.Errorf("upload test failed: %w", err)
@@ -79,84 +226,392 @@ func (st *SpeedTester) RunTest() (*SpeedTestResult, error) {
 		AvgDownloadSpeedMb: downloadSpeed,
 		AvgUploadLatency:   uploadLatency,
 		AvgDownloadLatency: downloadLatency,
+		Colo:               st.colo,
+		Concurrency:        maxInt(st.config.Concurrency, 1),
+		PacketSize:         st.config.PacketSize,
+		MinRTT:             pingStats.MinRTT,
+		MaxRTT:             pingStats.MaxRTT,
+		AvgRTT:             pingStats.AvgRTT,
+		Jitter:             pingStats.Jitter,
+		LossPercent:        pingStats.LossPercent,
 	}
 
 	return result, nil
 }
 
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// ipLatency holds the outcome of probing a single candidate IP.
+type ipLatency struct {
+	ip      string
+	colo    string
+	latency time.Duration
+	err     error
+}
+
+// SelectFastestIP pings each candidate IP pingsPerIP times against
+// speed.cloudflare.com/cdn-cgi/trace, subtracting server-side processing
+// time the same way testUpload/testDownload do, and reconfigures the
+// SpeedTester to use whichever IP has the lowest average latency. If
+// candidates is empty, it fetches Cloudflare's published IPv4 ranges and
+// probes one address per range. Probing fans out over a worker pool sized
+// by config.SelectConcurrency, with each probe bounded by
+// config.SelectTimeout.
+func (st *SpeedTester) SelectFastestIP(ctx context.Context, candidates []string, pingsPerIP int) (string, time.Duration, error) {
+	if len(candidates) == 0 {
+		fetched, err := fetchCloudflareIPv4Ranges(ctx, st.config)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to fetch cloudflare IP ranges: %w", err)
+		}
+		candidates = fetched
+	}
+
+	concurrency := st.config.SelectConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultSelectConcurrency
+	}
+	timeout := st.config.SelectTimeout
+	if timeout <= 0 {
+		timeout = defaultSelectTimeout
+	}
+
+	jobs := make(chan string)
+	results := make(chan ipLatency)
+
+	var workers sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for ip := range jobs {
+				results <- st.probeIP(ctx, ip, pingsPerIP, timeout)
+			}
+		}()
+	}
+
+	go func() {
+		for _, ip := range candidates {
+			jobs <- ip
+		}
+		close(jobs)
+	}()
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	var best ipLatency
+	found := false
+	for res := range results {
+		if res.err != nil {
+			if st.config.Verbose {
+				fmt.Printf("probe %s failed: %v\n", res.ip, res.err)
+			}
+			continue
+		}
+		if !found || res.latency < best.latency {
+			best = res
+			found = true
+		}
+	}
+
+	if !found {
+		return "", 0, fmt.Errorf("no reachable IP found among %d candidates", len(candidates))
+	}
+
+	st.config.CloudflareIP = best.ip
+	st.colo = best.colo
+
+	client, err := newClient(st.config)
+	if err != nil {
+		return "", 0, err
+	}
+	st.client = client
+
+	return best.ip, best.latency, nil
+}
+
+// probeIP sends pings GET requests for the Cloudflare trace endpoint over a
+// connection pinned to ip, returning the average latency and the colo
+// reported in the trace body. The client is built the same way as the rest
+// of the test (newClient), so it honors config.Proxy/SourceIP too; with
+// config.Proxy set, requests go through the proxy and are not pinned to ip.
+func (st *SpeedTester) probeIP(ctx context.Context, ip string, pings int, timeout time.Duration) ipLatency {
+	cfg := st.config
+	cfg.CloudflareIP = ip
+
+	client, err := newClient(cfg)
+	if err != nil {
+		return ipLatency{ip: ip, err: err}
+	}
+	client.Timeout = timeout
+
+	var total time.Duration
+	var colo string
+	successes := 0
+
+	for i := 0; i < pings; i++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", cloudflareTraceURL, nil)
+		if err != nil {
+			return ipLatency{ip: ip, err: err}
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+
+		latency := time.Since(start) - st.getServerTiming(&resp.Header)
+		if colo == "" {
+			colo = parseColoFromTrace(resp.Body)
+		}
+		resp.Body.Close()
+
+		total += latency
+		successes++
+	}
+
+	if successes == 0 {
+		return ipLatency{ip: ip, err: fmt.Errorf("all %d probes failed", pings)}
+	}
+
+	return ipLatency{ip: ip, colo: colo, latency: total / time.Duration(successes)}
+}
+
+// parseColoFromTrace scans a cdn-cgi/trace response body for its "colo=" line.
+func parseColoFromTrace(body io.Reader) string {
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		if colo, ok := strings.CutPrefix(scanner.Text(), "colo="); ok {
+			return colo
+		}
+	}
+	return ""
+}
+
+// fetchCloudflareIPv4Ranges downloads Cloudflare's published IPv4 CIDR
+// ranges and returns one probeable address per range. The client is built
+// the same way as the rest of the test (newClient), so it honors
+// config.Proxy/SourceIP too; config.CloudflareIP is cleared first since it
+// names a candidate being evaluated, not this request's destination.
+func fetchCloudflareIPv4Ranges(ctx context.Context, config SpeedTestConfig) ([]string, error) {
+	config.CloudflareIP = ""
+	client, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", cloudflareIPv4RangesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var ips []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		cidr := strings.TrimSpace(scanner.Text())
+		if cidr == "" {
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+
+		if ip := firstHostIP(ipNet); ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no usable IPs found in Cloudflare IP range list")
+	}
+
+	return ips, nil
+}
+
+// firstHostIP returns the first usable address within ipNet, or "" when
+// ipNet is not an IPv4 network.
+func firstHostIP(ipNet *net.IPNet) string {
+	ip := ipNet.IP.To4()
+	if ip == nil {
+		return ""
+	}
+
+	host := make(net.IP, len(ip))
+	copy(host, ip)
+	host[len(host)-1]++
+
+	return host.String()
+}
+
 func (st *SpeedTester) testUpload() (float64, time.Duration, error) {
+	return st.runParallel(st.config.Concurrency, st.config.PacketSize, st.config.PacketCount, func(n int) (time.Duration, error) {
+		return st.uploadBatch(st.config.PacketSize, n)
+	})
+}
+
+func (st *SpeedTester) testDownload() (float64, time.Duration, error) {
+	return st.runParallel(st.config.Concurrency, st.config.PacketSize, st.config.PacketCount, func(n int) (time.Duration, error) {
+		return st.downloadBatch(st.config.PacketSize, n)
+	})
+}
+
+// runParallel splits packetCount requests across concurrency goroutines
+// (default 1), running batch for each goroutine's share, and aggregates the
+// result into an overall Mb/s figure (bytes moved over wall-clock elapsed
+// time) and the average per-request latency.
+func (st *SpeedTester) runParallel(concurrency, packetSize, packetCount int, batch func(n int) (time.Duration, error)) (float64, time.Duration, error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	counts := splitCount(packetCount, concurrency)
+
+	var wg sync.WaitGroup
+	var totalLatency int64 // nanoseconds
+	errs := make(chan error, concurrency)
+
+	start := time.Now()
+	for _, n := range counts {
+		if n == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			latency, err := batch(n)
+			if err != nil {
+				errs <- err
+				return
+			}
+			atomic.AddInt64(&totalLatency, int64(latency))
+		}(n)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return 0, 0, err
+	}
+
+	totalBytes := int64(packetSize) * int64(packetCount)
+	speedMb := (float64(totalBytes) / elapsed.Seconds()) / float64(125000)
+	avgLatency := time.Duration(totalLatency / int64(packetCount))
+
+	return speedMb, avgLatency, nil
+}
+
+// splitCount divides total as evenly as possible across n buckets.
+func splitCount(total, n int) []int {
+	counts := make([]int, n)
+	base := total / n
+	remainder := total % n
+	for i := range counts {
+		counts[i] = base
+		if i < remainder {
+			counts[i]++
+		}
+	}
+	return counts
+}
+
+// uploadBatch performs n sequential upload requests and returns their
+// summed latency (server time subtracted out).
+func (st *SpeedTester) uploadBatch(packetSize, n int) (time.Duration, error) {
 	uploadURL := fmt.Sprintf("https://speed.cloudflare.com/__up?measId=%d", st.testID)
-	rawBody := bytes.Repeat([]byte{0x30}, st.config.PacketSize)
+	rawBody := bytes.Repeat([]byte{0x30}, packetSize)
 
 	var totalLatency time.Duration
 
-	for i := 0; i < st.config.PacketCount; i++ {
-
+	for i := 0; i < n; i++ {
 		req, err := http.NewRequest("POST", uploadURL, strings.NewReader(string(rawBody)))
-		req.Header.Add("Content-Type", "text/plain;charset=UTF-8")
-
 		if err != nil {
-			return 0, 0, fmt.Errorf("failed to create upload request: %w", err)
+			return 0, fmt.Errorf("failed to create upload request: %w", err)
 		}
+		req.Header.Add("Content-Type", "text/plain;charset=UTF-8")
 
 		startTime := time.Now()
 
 		resp, err := st.client.Do(req)
 		if err != nil {
-			return 0, 0, fmt.Errorf("failed to perform upload request: %w", err)
+			return 0, fmt.Errorf("failed to perform upload request: %w", err)
 		}
 
 		latency := time.Since(startTime)
-
 		totalLatency += (latency - st.getServerTiming(&resp.Header))
+		resp.Body.Close()
 
 		if st.config.Verbose {
-			fmt.Printf("Upload %d bytes in %s (server time: %s)\n", st.config.PacketSize, latency, st.getServerTiming(&resp.Header))
+			fmt.Printf("Upload %d bytes in %s (server time: %s)\n", packetSize, latency, st.getServerTiming(&resp.Header))
 		}
 	}
-	speedMb := (float64(st.config.PacketSize*st.config.PacketCount) / totalLatency.Seconds()) / float64(125000)
-	avgLatency := totalLatency / time.Duration(st.config.PacketCount)
 
-	return speedMb, avgLatency, nil
+	return totalLatency, nil
 }
 
-func (st *SpeedTester) testDownload() (float64, time.Duration, error) {
-
-	downloadURL := fmt.Sprintf("https://speed.cloudflare.com/__down?measId=%d&bytes=%d", st.testID, st.config.PacketSize)
+// downloadBatch performs n sequential download requests and returns their
+// summed latency (server time subtracted out).
+func (st *SpeedTester) downloadBatch(packetSize, n int) (time.Duration, error) {
+	downloadURL := fmt.Sprintf("https://speed.cloudflare.com/__down?measId=%d&bytes=%d", st.testID, packetSize)
 
 	var totalLatency time.Duration
-	for i := 0; i < st.config.PacketCount; i++ {
 
+	for i := 0; i < n; i++ {
 		req, err := http.NewRequest("GET", downloadURL, nil)
-
 		if err != nil {
-			return 0, 0, fmt.Errorf("failed to create download request: %w", err)
+			return 0, fmt.Errorf("failed to create download request: %w", err)
 		}
 
 		startTime := time.Now()
 
 		resp, err := st.client.Do(req)
 		if err != nil {
-			return 0, 0, fmt.Errorf("failed to perform download request: %w", err)
+			return 0, fmt.Errorf("failed to perform download request: %w", err)
 		}
 
 		latency := time.Since(startTime)
-
 		totalLatency += (latency - st.getServerTiming(&resp.Header))
+		resp.Body.Close()
 
 		if st.config.Verbose {
-			fmt.Printf("Download %d bytes in %s (server time: %s)\n", st.config.PacketSize, latency, st.getServerTiming(&resp.Header))
+			fmt.Printf("Download %d bytes in %s (server time: %s)\n", packetSize, latency, st.getServerTiming(&resp.Header))
 		}
 	}
 
-	speedMb := (float64(st.config.PacketSize*st.config.PacketCount) / totalLatency.Seconds()) / float64(125000)
-	avgLatency := totalLatency / time.Duration(st.config.PacketCount)
-
-	return speedMb, avgLatency, nil
+	return totalLatency, nil
 }
 
-// getServerTiming Extract server time from response headers
+// getServerTiming Extract server time from response headers. Returns 0 when
+// the header is missing or malformed, since not every endpoint this package
+// probes (e.g. /cdn-cgi/trace) is guaranteed to emit it.
 func (st *SpeedTester) getServerTiming(headers *http.Header) time.Duration {
-	i, _ := strconv.ParseFloat(strings.Split(headers.Get("Server-Timing"), "=")[1], 32)
+	parts := strings.Split(headers.Get("Server-Timing"), "=")
+	if len(parts) < 2 {
+		return 0
+	}
+
+	i, err := strconv.ParseFloat(parts[1], 32)
+	if err != nil {
+		return 0
+	}
+
 	return time.Duration(math.Round(i)) * time.Millisecond
 }
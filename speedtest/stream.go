@@ -0,0 +1,266 @@
+package speedtest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Duration bounds for RunTestStream, mirroring tailscale's net/speedtest.
+const (
+	MinDuration     = 1 * time.Second
+	DefaultDuration = 10 * time.Second
+	MaxDuration     = 30 * time.Second
+
+	defaultReportInterval = 1 * time.Second
+	downloadBufferSize    = 2 * 1024 * 1024 // 2 MiB, reused across reads to avoid syscall overhead
+	streamDownloadBytes   = 1 << 30         // upper bound on a single streamed download; duration cuts it short
+)
+
+// Direction identifies which half of a streamed test a Result belongs to.
+type Direction string
+
+const (
+	Upload   Direction = "upload"
+	Download Direction = "download"
+)
+
+// Result is one measurement emitted on the channel returned by
+// RunTestStream. Interval records report how many bytes moved during
+// [IntervalStart, IntervalEnd); each direction's stream ends with a single
+// Total record (Total == true) covering the whole run, which carries Err if
+// that direction failed.
+type Result struct {
+	Direction     Direction
+	IntervalStart time.Time
+	IntervalEnd   time.Time
+	Bytes         int64
+	Total         bool
+	Err           error
+}
+
+// RunTestStream runs an upload test followed by a download test, each for
+// config.Duration (clamped to [MinDuration, MaxDuration], DefaultDuration
+// when unset), and streams interval byte counts over the returned channel
+// every config.ReportInterval (defaultReportInterval when unset). The
+// channel is closed once both directions have finished; if the upload
+// fails, the download is not run and the channel closes right after the
+// upload's Total record. When
+// config.MeasureLoss is set, it runs Ping first (the same as RunTest) and
+// returns its stats alongside the channel, since the streamed Results have
+// no room to carry them.
+func (st *SpeedTester) RunTestStream(ctx context.Context) (<-chan Result, PingStats, error) {
+	var pingStats PingStats
+	if st.config.MeasureLoss {
+		stats, err := st.Ping(ctx, defaultPingCount)
+		if err != nil {
+			return nil, PingStats{}, fmt.Errorf("ping failed: %w", err)
+		}
+		pingStats = stats
+	}
+
+	duration := st.config.Duration
+	if duration <= 0 {
+		duration = DefaultDuration
+	}
+	if duration < MinDuration {
+		duration = MinDuration
+	}
+	if duration > MaxDuration {
+		duration = MaxDuration
+	}
+
+	interval := st.config.ReportInterval
+	if interval <= 0 {
+		interval = defaultReportInterval
+	}
+
+	results := make(chan Result)
+
+	go func() {
+		defer close(results)
+
+		if err := st.streamDirection(ctx, Upload, duration, interval, results); err != nil {
+			if st.config.Verbose {
+				fmt.Printf("upload stream failed: %v\n", err)
+			}
+			return
+		}
+
+		if err := st.streamDirection(ctx, Download, duration, interval, results); err != nil {
+			if st.config.Verbose {
+				fmt.Printf("download stream failed: %v\n", err)
+			}
+			return
+		}
+	}()
+
+	return results, pingStats, nil
+}
+
+// streamDirection runs one direction of the test for duration across
+// st.config.Concurrency simultaneous transfers (default 1), emitting an
+// interval Result every tick of interval and a final Total Result once all
+// of them finish.
+func (st *SpeedTester) streamDirection(ctx context.Context, dir Direction, duration, interval time.Duration, results chan<- Result) error {
+	runCtx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	concurrency := st.config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var counter int64
+	done := make(chan error, concurrency)
+
+	for i := 0; i < concurrency; i++ {
+		switch dir {
+		case Upload:
+			go func() { done <- st.uploadUntilDone(runCtx, &counter) }()
+		case Download:
+			go func() { done <- st.downloadUntilDone(runCtx, &counter) }()
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	last := start
+	var lastBytes int64
+	var finalErr error
+	remaining := concurrency
+
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			current := atomic.LoadInt64(&counter)
+			results <- Result{
+				Direction:     dir,
+				IntervalStart: last,
+				IntervalEnd:   now,
+				Bytes:         current - lastBytes,
+			}
+			last = now
+			lastBytes = current
+		case err := <-done:
+			remaining--
+			if err != nil && finalErr == nil {
+				finalErr = err
+			}
+			if remaining == 0 {
+				break loop
+			}
+		}
+	}
+
+	results <- Result{
+		Direction:     dir,
+		IntervalStart: start,
+		IntervalEnd:   time.Now(),
+		Bytes:         atomic.LoadInt64(&counter),
+		Total:         true,
+		Err:           finalErr,
+	}
+
+	return finalErr
+}
+
+// zeroReader yields an endless stream of ASCII '0' (0x30) bytes until ctx is done, so
+// the upload body can be streamed into a single POST for the full duration
+// instead of being rebuilt per request.
+type zeroReader struct {
+	ctx context.Context
+}
+
+func (z *zeroReader) Read(p []byte) (int, error) {
+	select {
+	case <-z.ctx.Done():
+		return 0, io.EOF
+	default:
+	}
+	for i := range p {
+		p[i] = 0x30
+	}
+	return len(p), nil
+}
+
+// countingReader wraps an io.Reader, tallying bytes read into counter.
+type countingReader struct {
+	r       io.Reader
+	counter *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		atomic.AddInt64(c.counter, int64(n))
+	}
+	return n, err
+}
+
+// uploadUntilDone streams zeroReader's '0' bytes into a single POST until ctx expires.
+func (st *SpeedTester) uploadUntilDone(ctx context.Context, counter *int64) error {
+	uploadURL := fmt.Sprintf("https://speed.cloudflare.com/__up?measId=%d", st.testID)
+
+	body := &countingReader{r: &zeroReader{ctx: ctx}, counter: counter}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, io.NopCloser(body))
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
+	req.ContentLength = -1
+
+	resp, err := st.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to perform upload request: %w", err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// downloadUntilDone reads a single large download response in reusable
+// downloadBufferSize blocks, tallying bytes until ctx expires or the body
+// ends.
+func (st *SpeedTester) downloadUntilDone(ctx context.Context, counter *int64) error {
+	downloadURL := fmt.Sprintf("https://speed.cloudflare.com/__down?measId=%d&bytes=%d", st.testID, streamDownloadBytes)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create download request: %w", err)
+	}
+
+	resp, err := st.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to perform download request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	buf := make([]byte, downloadBufferSize)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			atomic.AddInt64(counter, int64(n))
+		}
+		if err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("download stream read failed: %w", err)
+		}
+	}
+}
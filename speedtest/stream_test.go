@@ -0,0 +1,79 @@
+package speedtest
+
+import (
+	"context"
+	"io"
+	"testing"
+)
+
+func TestZeroReaderFillsBuffer(t *testing.T) {
+	z := &zeroReader{ctx: context.Background()}
+
+	buf := make([]byte, 16)
+	n, err := z.Read(buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if n != len(buf) {
+		t.Fatalf("Read returned n=%d, want %d", n, len(buf))
+	}
+	for i, b := range buf {
+		if b != 0x30 {
+			t.Fatalf("buf[%d] = %#x, want 0x30", i, b)
+		}
+	}
+}
+
+func TestZeroReaderStopsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	z := &zeroReader{ctx: ctx}
+
+	n, err := z.Read(make([]byte, 16))
+	if n != 0 || err != io.EOF {
+		t.Fatalf("Read() = (%d, %v), want (0, io.EOF)", n, err)
+	}
+}
+
+type constReader struct {
+	n   int
+	err error
+}
+
+func (c constReader) Read(p []byte) (int, error) {
+	return c.n, c.err
+}
+
+func TestCountingReaderTalliesBytes(t *testing.T) {
+	var counter int64
+	c := &countingReader{r: constReader{n: 5, err: io.EOF}, counter: &counter}
+
+	n, err := c.Read(make([]byte, 10))
+	if n != 5 || err != io.EOF {
+		t.Fatalf("Read() = (%d, %v), want (5, io.EOF)", n, err)
+	}
+	if counter != 5 {
+		t.Errorf("counter = %d, want 5", counter)
+	}
+
+	// A second read should accumulate rather than overwrite.
+	if _, err := c.Read(make([]byte, 10)); err != io.EOF {
+		t.Fatalf("second Read err = %v, want io.EOF", err)
+	}
+	if counter != 10 {
+		t.Errorf("counter after second read = %d, want 10", counter)
+	}
+}
+
+func TestCountingReaderIgnoresZeroByteReads(t *testing.T) {
+	var counter int64
+	c := &countingReader{r: constReader{n: 0, err: io.EOF}, counter: &counter}
+
+	if _, err := c.Read(make([]byte, 10)); err != io.EOF {
+		t.Fatalf("Read err = %v, want io.EOF", err)
+	}
+	if counter != 0 {
+		t.Errorf("counter = %d, want 0", counter)
+	}
+}